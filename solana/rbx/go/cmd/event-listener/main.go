@@ -2,67 +2,121 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"math/rand"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 	"github.com/gagliardetto/solana-go/rpc/ws"
+
+	"github.com/alex-sumner/solana-rabbitx-contract/solana/rbx/go/emit"
+	"github.com/alex-sumner/solana-rabbitx-contract/solana/rbx/go/events"
+	"github.com/alex-sumner/solana-rabbitx-contract/solana/rbx/go/health"
+	"github.com/alex-sumner/solana-rabbitx-contract/solana/rbx/go/storage"
+	"github.com/alex-sumner/solana-rabbitx-contract/solana/rbx/go/watcher"
 )
 
 const (
 	PROGRAM_ID = "CZBh9LezU7rC2vpxCBs8w1TSFYmHDjU2WmWYkkcocq9W"
-	// PROGRAM_ID         = "BEFhXGhAD2iqvwK8kQ5ubdzhWqwN5cqKA8XRgAN4C2Mj"
-	// DEFAULT_RPC        = "https://solana-devnet.g.alchemy.com/v2/8xgHgvv1JXGWJhS3ErUmZI5QN0VT2HPD"
-	DEFAULT_RPC        = "wss://api.mainnet-beta.solana.com"
-	POLL_INTERVAL      = 10 * time.Second
-	RATE_LIMIT_BACKOFF = 30 * time.Second
-)
+	// PROGRAM_ID  = "BEFhXGhAD2iqvwK8kQ5ubdzhWqwN5cqKA8XRgAN4C2Mj"
+	DEFAULT_RPC         = "https://solana-devnet.g.alchemy.com/v2/8xgHgvv1JXGWJhS3ErUmZI5QN0VT2HPD"
+	DEFAULT_WS          = "wss://solana-devnet.g.alchemy.com/v2/8xgHgvv1JXGWJhS3ErUmZI5QN0VT2HPD"
+	DEFAULT_IDL         = "solana/rbx/idl/rabbitx_vault.json"
+	DEFAULT_DB          = "event_listener_cursor.db"
+	DEFAULT_HEALTH_ADDR = ":9101"
 
-type Config struct {
-	RPCEndpoint  string
-	ProgramID    string
-	PollInterval time.Duration
-}
+	// pruneInterval and pruneRetentionSlots bound how much signature history
+	// the cursor DB keeps; anything older than ~10 days at mainnet slot
+	// times is reclaimed.
+	pruneInterval       = time.Hour
+	pruneRetentionSlots = 2_000_000
 
-type DepositEvent struct {
-	ID     string           `json:"id"`
-	Trader solana.PublicKey `json:"trader"`
-	Amount uint64           `json:"amount"`
-	Token  solana.PublicKey `json:"token"`
-}
+	// wsInitialBackoff and wsMaxBackoff bound the jittered exponential
+	// backoff applied between reconnect attempts.
+	wsInitialBackoff = 1 * time.Second
+	wsMaxBackoff     = 30 * time.Second
+)
 
-type WithdrawalEvent struct {
-	ID     uint64           `json:"id"`
-	Trader solana.PublicKey `json:"trader"`
-	Amount uint64           `json:"amount"`
-	Token  solana.PublicKey `json:"token"`
+type Config struct {
+	RPCEndpoint string
+	WSEndpoint  string
+	ProgramID   string
+	IDLPath     string
+	DBPath      string
+	Reset       bool
+	SinksConfig string
+	HealthAddr  string
 }
 
 func main() {
 	// Parse command line flags
-	rpcEndpoint := flag.String("rpc", DEFAULT_RPC, "Solana RPC endpoint (WebSocket URL)")
+	rpcEndpoint := flag.String("rpc", DEFAULT_RPC, "Solana RPC endpoint (HTTP URL), used to gap-fill events missed while disconnected")
+	wsEndpoint := flag.String("ws", DEFAULT_WS, "Solana RPC endpoint (WebSocket URL), used for the live log subscription")
 	programID := flag.String("program", PROGRAM_ID, "Program ID to monitor")
-	pollInterval := flag.Duration("interval", POLL_INTERVAL, "Polling interval (fallback)")
+	idlPath := flag.String("idl", DEFAULT_IDL, "Path to the Anchor IDL JSON file describing program events")
+	dbPath := flag.String("db", DEFAULT_DB, "Path to the BoltDB signature cursor file")
+	reset := flag.Bool("reset", false, "Discard any persisted cursor and start from the current slot")
+	sinksConfig := flag.String("config", "", "Path to a sinks.yaml fanning decoded events out to webhook/NATS/Kafka/file sinks (optional)")
+	healthAddr := flag.String("health-addr", DEFAULT_HEALTH_ADDR, "Address to serve /healthz and /metrics on")
 	flag.Parse()
 
 	config := Config{
-		RPCEndpoint:  *rpcEndpoint,
-		ProgramID:    *programID,
-		PollInterval: *pollInterval,
+		RPCEndpoint: *rpcEndpoint,
+		WSEndpoint:  *wsEndpoint,
+		ProgramID:   *programID,
+		IDLPath:     *idlPath,
+		DBPath:      *dbPath,
+		Reset:       *reset,
+		SinksConfig: *sinksConfig,
+		HealthAddr:  *healthAddr,
 	}
 
-	log.Printf("Starting event listener with WebSocket endpoint: %s", config.RPCEndpoint)
+	log.Printf("Starting event listener with WebSocket endpoint: %s", config.WSEndpoint)
 	log.Printf("Monitoring program: %s", config.ProgramID)
 	log.Printf("Press Ctrl+C to stop")
 
+	registry, err := events.LoadRabbitXRegistry(config.IDLPath)
+	if err != nil {
+		log.Fatalf("Failed to load event registry: %v", err)
+	}
+
+	if config.Reset {
+		if err := os.Remove(config.DBPath); err != nil && !os.IsNotExist(err) {
+			log.Fatalf("Failed to reset cursor file %s: %v", config.DBPath, err)
+		}
+	}
+	store, err := storage.NewBoltStore(config.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to open cursor store: %v", err)
+	}
+	defer store.Close()
+
+	var sink emit.Sink = emit.NopSink{}
+	if config.SinksConfig != "" {
+		fanOut, err := emit.LoadConfig(config.SinksConfig)
+		if err != nil {
+			log.Fatalf("Failed to load sinks config %s: %v", config.SinksConfig, err)
+		}
+		defer fanOut.Close()
+		sink = fanOut
+	}
+
+	status := health.NewStatus()
+	go func() {
+		if err := health.Serve(config.HealthAddr, status); err != nil {
+			log.Printf("Health server on %s stopped: %v", config.HealthAddr, err)
+		}
+	}()
+
+	httpClient := rpc.New(config.RPCEndpoint)
+	w := watcher.New(httpClient, solana.MustPublicKeyFromBase58(config.ProgramID), registry)
+
 	// Create a context that can be cancelled
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -71,10 +125,11 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	// Start the event listener in a goroutine
+	go prunePeriodically(ctx, store)
+
 	errChan := make(chan error, 1)
 	go func() {
-		errChan <- runEventListener(ctx, config, sigChan)
+		errChan <- runEventListener(ctx, config, registry, store, sink, httpClient, w, status)
 	}()
 
 	// Wait for either a signal or an error
@@ -89,127 +144,304 @@ func main() {
 	}
 }
 
-func runEventListener(ctx context.Context, config Config, sigChan chan os.Signal) error {
-	// Create a new WebSocket client
-	client, err := ws.Connect(ctx, config.RPCEndpoint)
+// runEventListener supervises the live WebSocket log subscription: on
+// connect failure or a Recv error, it closes the client, backs off with
+// jittered exponential backoff (capped at wsMaxBackoff), reconnects, and
+// resubscribes. After every successful (re)connect it backfills the gap
+// between the last slot seen live and the current slot over HTTP and
+// de-duplicates the result against the persistent signature store, so a
+// dropped connection never silently loses events.
+func runEventListener(ctx context.Context, config Config, registry *events.Registry, store storage.Store, sink emit.Sink, httpClient *rpc.Client, w *watcher.Watcher, status *health.Status) error {
+	programID := solana.MustPublicKeyFromBase58(config.ProgramID)
+
+	// The WS subscription only ever observes confirmed commitment, so the gap
+	// it needs filled is measured against the confirmed watermark.
+	lastSlot, _, err := store.LastSlot(string(rpc.CommitmentConfirmed))
 	if err != nil {
-		return fmt.Errorf("failed to connect to WebSocket: %v", err)
+		return fmt.Errorf("reading persisted cursor: %w", err)
 	}
-	defer client.Close()
 
-	// Subscribe to program logs
-	programID := solana.MustPublicKeyFromBase58(config.ProgramID)
-	sub, err := client.ProgramSubscribe(
-		programID,
-		rpc.CommitmentConfirmed,
-	)
+	backoff := wsInitialBackoff
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		wsClient, sub, err := connectAndSubscribe(ctx, config.WSEndpoint, programID)
+		if err != nil {
+			watcher.WSReconnectsTotal.Inc()
+			log.Printf("WebSocket connect failed: %v; retrying in %s", err, backoff)
+			if !sleepOrDone(ctx, jittered(backoff)) {
+				return ctx.Err()
+			}
+			backoff = nextBackoff(backoff)
+			continue
+		}
+
+		status.SetConnected(true)
+		backoff = wsInitialBackoff
+
+		if lastSlot > 0 {
+			if err := backfillGap(ctx, httpClient, w, lastSlot, store, sink); err != nil {
+				log.Printf("Gap-fill backfill after (re)connect failed: %v", err)
+			}
+		}
+
+		newLastSlot, recvErr := consumeLogs(ctx, sub, registry, store, sink, lastSlot)
+		if newLastSlot > lastSlot {
+			lastSlot = newLastSlot
+		}
+		wsClient.Close()
+		status.SetConnected(false)
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		watcher.WSReconnectsTotal.Inc()
+		log.Printf("WebSocket subscription ended (%v); reconnecting in %s", recvErr, backoff)
+		if !sleepOrDone(ctx, jittered(backoff)) {
+			return ctx.Err()
+		}
+		backoff = nextBackoff(backoff)
+	}
+}
+
+// connectAndSubscribe opens a fresh WebSocket connection and subscribes to
+// logs mentioning programID at confirmed commitment.
+func connectAndSubscribe(ctx context.Context, endpoint string, programID solana.PublicKey) (*ws.Client, *ws.LogSubscription, error) {
+	client, err := ws.Connect(ctx, endpoint)
 	if err != nil {
-		return fmt.Errorf("failed to subscribe to program: %v", err)
+		return nil, nil, fmt.Errorf("connecting to %s: %w", endpoint, err)
 	}
-	defer sub.Unsubscribe()
 
-	log.Printf("Successfully subscribed to program logs")
+	sub, err := client.LogsSubscribeMentions(programID, rpc.CommitmentConfirmed)
+	if err != nil {
+		client.Close()
+		return nil, nil, fmt.Errorf("subscribing to program logs: %w", err)
+	}
+
+	log.Printf("Subscribed to live logs for %s", programID)
+	return client, sub, nil
+}
+
+// backfillGap replays (fromSlot, currentSlot] over HTTP so anything that
+// happened while disconnected (or before the first live notification
+// arrives) still reaches the cursor store and the configured sinks.
+func backfillGap(ctx context.Context, httpClient *rpc.Client, w *watcher.Watcher, fromSlot uint64, store storage.Store, sink emit.Sink) error {
+	currentSlot, err := httpClient.GetSlot(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return fmt.Errorf("resolving current slot: %w", err)
+	}
+	if currentSlot <= fromSlot {
+		return nil
+	}
+
+	log.Printf("Backfilling gap [%d, %d] before resuming live consumption", fromSlot+1, currentSlot)
+
+	publications := make(chan watcher.MessagePublication, 64)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		drainPublications(ctx, publications, store, sink)
+	}()
+
+	err = w.Reobserve(ctx, fromSlot+1, currentSlot, publications)
+	close(publications)
+	<-done
+	return err
+}
+
+// consumeLogs reads live log notifications until the subscription errors or
+// ctx is cancelled, returning the highest slot it saw so the caller can
+// gap-fill from there on the next reconnect.
+func consumeLogs(ctx context.Context, sub *ws.LogSubscription, registry *events.Registry, store storage.Store, sink emit.Sink, lastSlot uint64) (uint64, error) {
+	type recvResult struct {
+		res *ws.LogResult
+		err error
+	}
+	results := make(chan recvResult, 16)
+	go func() {
+		for {
+			res, err := sub.Recv()
+			results <- recvResult{res, err}
+			if err != nil {
+				return
+			}
+		}
+	}()
 
-	// Process events
 	for {
 		select {
 		case <-ctx.Done():
-			log.Printf("Context cancelled, shutting down...")
-			return ctx.Err()
-		case <-sigChan:
-			log.Printf("Received signal, shutting down...")
-			return fmt.Errorf("received termination signal")
-		default:
-			result, err := sub.Recv(ctx)
-			if err != nil {
-				log.Printf("Error receiving from subscription: %v", err)
-				continue
+			return lastSlot, ctx.Err()
+		case r := <-results:
+			if r.err != nil {
+				return lastSlot, r.err
 			}
-
-			// Convert to JSON and extract logs through JSON parsing
-			if result != nil {
-				// Convert the entire result to JSON
-				resultJSON, err := json.Marshal(result)
-				if err != nil {
-					log.Printf("Error marshaling result: %v", err)
-					continue
-				}
-
-				// Parse as a generic map to navigate the structure
-				var resultMap map[string]interface{}
-				if err := json.Unmarshal(resultJSON, &resultMap); err != nil {
-					log.Printf("Error parsing result JSON: %v", err)
-					continue
-				}
-
-				// Try to extract logs from the JSON structure
-				var logs []string
-
-				// Try to navigate the JSON structure to find logs
-				if valueObj, ok := resultMap["Value"].(map[string]interface{}); ok {
-					// Try different paths where logs might be found
-					if logsArray, ok := valueObj["Logs"].([]interface{}); ok {
-						for _, logEntry := range logsArray {
-							if logStr, ok := logEntry.(string); ok {
-								logs = append(logs, logStr)
-							}
-						}
-					}
-				}
-
-				// Process any logs we found
-				for _, logLine := range logs {
-					// Check for DepositEvent
-					if strings.Contains(logLine, "DepositEvent") {
-						var event DepositEvent
-						if err := parseEvent(logLine, &event); err != nil {
-							log.Printf("Failed to parse DepositEvent: %v", err)
-							continue
-						}
-						handleDepositEvent(event)
-					}
-
-					// Check for WithdrawalEvent
-					if strings.Contains(logLine, "WithdrawalEvent") {
-						var event WithdrawalEvent
-						if err := parseEvent(logLine, &event); err != nil {
-							log.Printf("Failed to parse WithdrawalEvent: %v", err)
-							continue
-						}
-						handleWithdrawalEvent(event)
-					}
-				}
+			if r.res.Context.Slot > lastSlot {
+				lastSlot = r.res.Context.Slot
 			}
+			handleLiveLogResult(ctx, r.res, registry, store, sink)
+		}
+	}
+}
+
+// handleLiveLogResult decodes and publishes the events (if any) found in a
+// single live logsSubscribe notification, de-duplicating against the
+// persistent cursor store exactly like the gap-fill path.
+func handleLiveLogResult(ctx context.Context, res *ws.LogResult, registry *events.Registry, store storage.Store, sink emit.Sink) {
+	if res.Value.Err != nil {
+		return
+	}
+
+	decoded := registry.DecodeLogs(res.Value.Logs)
+	if len(decoded) == 0 {
+		return
+	}
+
+	pub := watcher.MessagePublication{
+		Slot:       res.Context.Slot,
+		Signature:  res.Value.Signature,
+		Commitment: rpc.CommitmentConfirmed,
+		Events:     decoded,
+	}
+	publishPublication(ctx, pub, store, sink)
+
+	watcher.EventsConfirmedTotal.Add(float64(len(decoded)))
+	watcher.CurrentSlot.WithLabelValues(string(rpc.CommitmentConfirmed)).Set(float64(pub.Slot))
+}
+
+// drainPublications reads publications (produced by watcher.Reobserve)
+// until the channel closes, publishing each one exactly like the live path.
+func drainPublications(ctx context.Context, publications <-chan watcher.MessagePublication, store storage.Store, sink emit.Sink) {
+	for pub := range publications {
+		publishPublication(ctx, pub, store, sink)
+	}
+}
+
+// publishPublication de-duplicates pub against the cursor store, prints and
+// forwards its decoded events to the configured sinks, and persists the
+// cursor. Shared by the live WebSocket path and the HTTP gap-fill backfill.
+func publishPublication(ctx context.Context, pub watcher.MessagePublication, store storage.Store, sink emit.Sink) {
+	key := cursorKey(pub)
+	seen, err := store.Seen(key)
+	if err != nil {
+		log.Printf("Failed to check cursor for %s: %v", key, err)
+	} else if seen {
+		return
+	}
+
+	for _, decoded := range pub.Events {
+		switch event := decoded.Value.(type) {
+		case *events.DepositEvent:
+			handleDepositEvent(pub, *event)
+		case *events.WithdrawalEvent:
+			handleWithdrawalEvent(pub, *event)
+		default:
+			log.Printf("Slot %d tx %s: decoded unhandled event %q: %+v",
+				pub.Slot, pub.Signature, decoded.Name, decoded.Value)
+		}
+
+		if err := sink.Publish(ctx, emit.Event{
+			ProgramID:  PROGRAM_ID,
+			Slot:       pub.Slot,
+			Signature:  pub.Signature.String(),
+			Commitment: string(pub.Commitment),
+			Name:       decoded.Name,
+			Data:       decoded.Value,
+		}); err != nil {
+			log.Printf("Failed to publish %s to sinks: %v", decoded.Name, err)
 		}
 	}
+
+	if err := store.Mark(key, pub.Slot, string(pub.Commitment)); err != nil {
+		log.Printf("Failed to persist cursor for %s: %v", key, err)
+	}
+}
+
+// sleepOrDone waits for d, returning false early (without sleeping the rest
+// of the way) if ctx is cancelled first.
+func sleepOrDone(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// jittered randomizes d by up to +/-20% so many instances reconnecting at
+// once don't all hammer the RPC endpoint in lockstep.
+func jittered(d time.Duration) time.Duration {
+	spread := float64(d) * 0.2
+	return d + time.Duration(rand.Float64()*2*spread-spread)
 }
 
-func parseEvent(log string, event interface{}) error {
-	// Extract the JSON part from the log
-	start := strings.Index(log, "{")
-	end := strings.LastIndex(log, "}") + 1
-	if start == -1 || end == 0 {
-		return fmt.Errorf("invalid log format")
+// nextBackoff doubles d, capped at wsMaxBackoff.
+func nextBackoff(d time.Duration) time.Duration {
+	d *= 2
+	if d > wsMaxBackoff {
+		d = wsMaxBackoff
 	}
+	return d
+}
+
+// cursorKey keys the cursor store by signature and commitment level, since
+// a signature can be (re)observed once per commitment level.
+func cursorKey(pub watcher.MessagePublication) string {
+	return pub.Signature.String() + ":" + string(pub.Commitment)
+}
+
+func prunePeriodically(ctx context.Context, store storage.Store) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
 
-	jsonStr := log[start:end]
-	return json.Unmarshal([]byte(jsonStr), event)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// The WS subscription only marks confirmed commitment, so that's
+			// the watermark to prune against here.
+			lastSlot, ok, err := store.LastSlot(string(rpc.CommitmentConfirmed))
+			if err != nil || !ok || lastSlot <= pruneRetentionSlots {
+				continue
+			}
+			pruned, err := store.Prune(lastSlot - pruneRetentionSlots)
+			if err != nil {
+				log.Printf("Cursor prune failed: %v", err)
+				continue
+			}
+			if pruned > 0 {
+				log.Printf("Pruned %d stale cursor entries", pruned)
+			}
+		}
+	}
 }
 
-func handleDepositEvent(event DepositEvent) {
-	log.Printf("Deposit Event: ID=%s, Trader=%s, Amount=%d, Token=%s",
+func handleDepositEvent(pub watcher.MessagePublication, event events.DepositEvent) {
+	log.Printf("[%s] Deposit Event: ID=%d, Trader=%s, Amount=%d, Token=%s, Slot=%d, Tx=%s",
+		pub.Commitment,
 		event.ID,
 		event.Trader.String(),
 		event.Amount,
 		event.Token.String(),
+		pub.Slot,
+		pub.Signature,
 	)
 }
 
-func handleWithdrawalEvent(event WithdrawalEvent) {
-	log.Printf("Withdrawal Event: ID=%d, Trader=%s, Amount=%d, Token=%s",
+func handleWithdrawalEvent(pub watcher.MessagePublication, event events.WithdrawalEvent) {
+	log.Printf("[%s] Withdrawal Event: ID=%d, Trader=%s, Amount=%d, Token=%s, Slot=%d, Tx=%s",
+		pub.Commitment,
 		event.ID,
 		event.Trader.String(),
 		event.Amount,
 		event.Token.String(),
+		pub.Slot,
+		pub.Signature,
 	)
 }
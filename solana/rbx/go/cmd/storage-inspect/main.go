@@ -0,0 +1,41 @@
+// Command storage-inspect dumps the on-disk signature cursor state for one
+// of the monitoring tools, for debugging what a restart would resume from.
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/alex-sumner/solana-rabbitx-contract/solana/rbx/go/storage"
+)
+
+const DEFAULT_DB = "rbx_cursor.db"
+
+func main() {
+	dbPath := flag.String("db", DEFAULT_DB, "Path to the BoltDB cursor file")
+	flag.Parse()
+
+	store, err := storage.NewBoltStore(*dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *dbPath, err)
+	}
+	defer store.Close()
+
+	stats, err := store.Stats()
+	if err != nil {
+		log.Fatalf("Failed to read stats: %v", err)
+	}
+
+	log.Printf("Cursor file: %s", *dbPath)
+	log.Printf("Tracked signatures: %d", stats.SignatureCount)
+	if stats.HasConfirmedSlot {
+		log.Printf("Last confirmed slot: %d", stats.LastConfirmedSlot)
+	} else {
+		log.Printf("Last confirmed slot: none")
+	}
+	if stats.HasFinalizedSlot {
+		log.Printf("Last finalized slot: %d", stats.LastFinalizedSlot)
+	} else {
+		log.Printf("Last finalized slot: none")
+	}
+}
@@ -13,39 +13,68 @@ import (
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/alex-sumner/solana-rabbitx-contract/solana/rbx/go/events"
+	"github.com/alex-sumner/solana-rabbitx-contract/solana/rbx/go/storage"
+	"github.com/alex-sumner/solana-rabbitx-contract/solana/rbx/go/watcher"
 )
 
 const (
-	PROGRAM_ID         = "9yWT9i8kJxY6JFdud9eeWkqtiMTUcDgbSCgF5RD4ihTE"
-	DEFAULT_RPC        = "https://solana-devnet.g.alchemy.com/v2/8xgHgvv1JXGWJhS3ErUmZI5QN0VT2HPD"
-	POLL_INTERVAL      = 30 * time.Second
-	RATE_LIMIT_BACKOFF = 30 * time.Second
-	DEFAULT_LIMIT      = 20
+	PROGRAM_ID    = "9yWT9i8kJxY6JFdud9eeWkqtiMTUcDgbSCgF5RD4ihTE"
+	DEFAULT_RPC   = "https://solana-devnet.g.alchemy.com/v2/8xgHgvv1JXGWJhS3ErUmZI5QN0VT2HPD"
+	DEFAULT_IDL   = "solana/rbx/idl/rabbitx_vault.json"
+	DEFAULT_DB    = "transaction_lister_cursor.db"
+	POLL_INTERVAL = 30 * time.Second
+
+	// DEFAULT_ONCE_SINCE is how far back --once walks when --since isn't
+	// given, enough to show recent activity without walking whole history.
+	DEFAULT_ONCE_SINCE = 5 * time.Minute
+
+	// DEFAULT_LIMIT caps how many transactions --once prints, matching the
+	// original tool's --limit.
+	DEFAULT_LIMIT = 20
+
+	// averageSlotTime is only used to translate --since into a starting
+	// slot estimate; --once walks whatever blocks actually exist in the
+	// resulting range.
+	averageSlotTime = 450 * time.Millisecond
 )
 
 type Config struct {
 	RPCEndpoint  string
 	ProgramID    string
+	IDLPath      string
+	DBPath       string
+	Reset        bool
 	PollInterval time.Duration
-	Limit        int
 	ShowOnce     bool
+	Since        time.Duration
+	Limit        int
 }
 
 func main() {
 	// Parse command line flags
 	rpcEndpoint := flag.String("rpc", DEFAULT_RPC, "Solana RPC endpoint (HTTP URL)")
 	programID := flag.String("program", PROGRAM_ID, "Program ID to monitor")
+	idlPath := flag.String("idl", DEFAULT_IDL, "Path to the Anchor IDL JSON file describing program events")
+	dbPath := flag.String("db", DEFAULT_DB, "Path to the BoltDB signature cursor file (continuous mode only)")
+	reset := flag.Bool("reset", false, "Discard any persisted cursor and start from the current slot")
 	pollInterval := flag.Duration("interval", POLL_INTERVAL, "Polling interval (for continuous mode)")
-	limit := flag.Int("limit", DEFAULT_LIMIT, "Maximum number of transactions to show")
 	showOnce := flag.Bool("once", false, "Show transactions once and exit (don't poll)")
+	since := flag.Duration("since", DEFAULT_ONCE_SINCE, "How far back --once walks to find transactions")
+	limit := flag.Int("limit", DEFAULT_LIMIT, "Maximum number of transactions --once prints")
 	flag.Parse()
 
 	config := Config{
 		RPCEndpoint:  *rpcEndpoint,
 		ProgramID:    *programID,
+		IDLPath:      *idlPath,
+		DBPath:       *dbPath,
+		Reset:        *reset,
 		PollInterval: *pollInterval,
-		Limit:        *limit,
 		ShowOnce:     *showOnce,
+		Since:        *since,
+		Limit:        *limit,
 	}
 
 	log.Printf("Starting transaction lister with HTTP endpoint: %s", config.RPCEndpoint)
@@ -55,6 +84,18 @@ func main() {
 		log.Printf("Press Ctrl+C to stop")
 	}
 
+	registry, err := events.LoadIDLFile(config.IDLPath)
+	if err != nil {
+		log.Fatalf("Failed to load IDL: %v", err)
+	}
+	eventRegistry := events.NewRegistry()
+	if err := eventRegistry.LoadIDL(registry); err != nil {
+		log.Fatalf("Failed to build event registry: %v", err)
+	}
+
+	client := rpc.New(config.RPCEndpoint)
+	w := watcher.New(client, solana.MustPublicKeyFromBase58(config.ProgramID), eventRegistry)
+
 	// Create a context that can be cancelled
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -67,15 +108,13 @@ func main() {
 	errChan := make(chan error, 1)
 	go func() {
 		if config.ShowOnce {
-			// Run once and exit
-			err := listTransactions(ctx, config)
-			if err != nil {
-				errChan <- err
-			}
+			// Run once and exit; a one-shot invocation has no restart to
+			// resume across, so it doesn't touch the cursor store.
+			listTransactionsOnce(ctx, client, w, config.Since, config.Limit)
 			cancel() // Signal completion
 		} else {
 			// Run in a polling loop
-			errChan <- runTransactionLister(ctx, config)
+			errChan <- runTransactionLister(ctx, w, config)
 		}
 	}()
 
@@ -93,132 +132,160 @@ func main() {
 	}
 }
 
-func runTransactionLister(ctx context.Context, config Config) error {
-	// Create a new HTTP client
-	client := rpc.New(config.RPCEndpoint)
+func runTransactionLister(ctx context.Context, w *watcher.Watcher, config Config) error {
+	if config.Reset {
+		if err := os.Remove(config.DBPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("resetting cursor file %s: %w", config.DBPath, err)
+		}
+	}
+	store, err := storage.NewBoltStore(config.DBPath)
+	if err != nil {
+		return fmt.Errorf("opening cursor store: %w", err)
+	}
+	defer store.Close()
 
-	// Keep track of the most recent signature we've seen
-	var lastSignature string
+	lastConfirmed, hasConfirmed, err := store.LastSlot(string(rpc.CommitmentConfirmed))
+	if err != nil {
+		return fmt.Errorf("reading persisted confirmed cursor: %w", err)
+	}
+	lastFinalized, hasFinalized, err := store.LastSlot(string(rpc.CommitmentFinalized))
+	if err != nil {
+		return fmt.Errorf("reading persisted finalized cursor: %w", err)
+	}
+	if hasConfirmed || hasFinalized {
+		log.Printf("Resuming from persisted slots (confirmed=%d, finalized=%d)", lastConfirmed, lastFinalized)
+		w.Resume(lastConfirmed, lastFinalized)
+	}
 
-	// Poll for transactions in a loop
-	ticker := time.NewTicker(config.PollInterval)
-	defer ticker.Stop()
+	publications := make(chan watcher.MessagePublication, 64)
+	reorgs := make(chan watcher.Reorged, 16)
+	go printAndPersistTransactions(publications, reorgs, store)
 
-	log.Printf("Starting polling loop...")
+	return w.Run(ctx, config.PollInterval, publications, reorgs)
+}
+
+// listTransactionsOnce walks a real historical slot range via Reobserve -
+// [currentSlot-lookback, currentSlot] - and prints whatever it finds, rather
+// than taking a PollOnce baseline and relying on a second poll to see slots
+// advance past it (which back-to-back almost never happens).
+func listTransactionsOnce(ctx context.Context, client *rpc.Client, w *watcher.Watcher, since time.Duration, limit int) {
+	publications := make(chan watcher.MessagePublication, 64)
+	reorgs := make(chan watcher.Reorged, 16)
+
+	done := make(chan struct{})
+	go func() {
+		printTransactions(publications, reorgs, limit)
+		close(done)
+	}()
+
+	currentSlot, err := client.GetSlot(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		log.Printf("Failed to resolve current slot: %v", err)
+		close(publications)
+		close(reorgs)
+		<-done
+		return
+	}
 
+	lookbackSlots := uint64(since / averageSlotTime)
+	var fromSlot uint64
+	if currentSlot > lookbackSlots {
+		fromSlot = currentSlot - lookbackSlots
+	}
+
+	if err := w.Reobserve(ctx, fromSlot, currentSlot, publications); err != nil {
+		log.Printf("Reobserve failed: %v", err)
+	}
+	close(publications)
+	close(reorgs)
+	<-done
+}
+
+// printAndPersistTransactions is printTransactions plus cursor bookkeeping,
+// used by the continuous polling mode so a restart resumes instead of
+// re-listing everything since the program's genesis.
+func printAndPersistTransactions(publications <-chan watcher.MessagePublication, reorgs <-chan watcher.Reorged, store storage.Store) {
 	for {
 		select {
-		case <-ctx.Done():
-			log.Printf("Context cancelled, shutting down...")
-			return ctx.Err()
-		case <-ticker.C:
-			// Show transactions and update lastSignature
-			var err error
-			lastSignature, err = showTransactions(ctx, client, config, lastSignature)
+		case pub, ok := <-publications:
+			if !ok {
+				return
+			}
+
+			key := pub.Signature.String() + ":" + string(pub.Commitment)
+			seen, err := store.Seen(key)
 			if err != nil {
-				log.Printf("Error getting transactions: %v", err)
+				log.Printf("Failed to check cursor for %s: %v", key, err)
+			} else if seen {
+				continue
+			}
+
+			fmt.Printf("%-65s | %-10s | %-6d | %s\n",
+				pub.Signature.String(),
+				pub.Commitment,
+				pub.Slot,
+				summarizeEvents(pub.Events),
+			)
 
-				// Check for rate limiting and back off if needed
-				if strings.Contains(err.Error(), "429") || strings.Contains(err.Error(), "rate limit") {
-					log.Printf("Rate limited. Backing off for %s", RATE_LIMIT_BACKOFF)
-					time.Sleep(RATE_LIMIT_BACKOFF)
-				}
+			if err := store.Mark(key, pub.Slot, string(pub.Commitment)); err != nil {
+				log.Printf("Failed to persist cursor for %s: %v", key, err)
 			}
+		case reorg, ok := <-reorgs:
+			if !ok {
+				return
+			}
+			fmt.Printf("REORG detected: signature %s at slot %d\n", reorg.Signature, reorg.Slot)
 		}
 	}
 }
 
-func listTransactions(ctx context.Context, config Config) error {
-	client := rpc.New(config.RPCEndpoint)
-	_, err := showTransactions(ctx, client, config, "")
-	return err
-}
-
-func showTransactions(ctx context.Context, client *rpc.Client, config Config, lastSignature string) (string, error) {
-	// Get signatures for the program
-	programID := solana.MustPublicKeyFromBase58(config.ProgramID)
-	
-	// Set up options
-	opts := &rpc.GetSignaturesForAddressOpts{
-		Limit: uint64(config.Limit),
-	}
-	
-	// If we have a last signature, start after it
-	if lastSignature != "" {
-		opts.Until = solana.MustSignatureFromBase58(lastSignature)
-	}
-	
-	sigs, err := client.GetSignaturesForAddress(ctx, programID, opts)
-	if err != nil {
-		return lastSignature, err
-	}
-	
-	if len(sigs) == 0 {
-		log.Printf("No transactions found")
-		return lastSignature, nil
-	}
-	
-	// Keep track of the most recent signature for next poll
-	newLastSignature := lastSignature
-	if len(sigs) > 0 && (newLastSignature == "" || sigs[0].Signature.String() != newLastSignature) {
-		newLastSignature = sigs[0].Signature.String()
-	}
-	
-	// Print header
+// printTransactions prints every publication and reorg it receives until both
+// channels close, stopping at the first limit (matching the original tool's
+// --limit) and draining the rest unprinted so the producer never blocks.
+func printTransactions(publications <-chan watcher.MessagePublication, reorgs <-chan watcher.Reorged, limit int) {
 	fmt.Println("\n=== Recent Transactions ===")
-	fmt.Printf("%-65s | %-30s | %-10s | %s\n", "Signature", "Block Time", "Status", "Memo")
+	fmt.Printf("%-65s | %-10s | %-6s | %s\n", "Signature", "Commitment", "Slot", "Events")
 	fmt.Println(strings.Repeat("-", 120))
-	
-	// Print transactions
-	for _, sig := range sigs {
-		// Format block time
-		var timeStr string
-		if sig.BlockTime != nil {
-			t := time.Unix(*sig.BlockTime, 0)
-			timeStr = t.Format("2006-01-02 15:04:05")
-		} else {
-			timeStr = "Unknown"
-		}
-		
-		// Get status string
-		status := "Success"
-		if sig.Err != nil {
-			status = fmt.Sprintf("Failed: %v", sig.Err)
-		}
-		
-		// Get transaction details for memo or additional info
-		memo := ""
-		if sig.Memo != nil {
-			memo = *sig.Memo
-		} else {
-			// For transactions without a memo, try to extract the instruction type
-			tx, err := client.GetTransaction(ctx, sig.Signature, &rpc.GetTransactionOpts{
-				Commitment: rpc.CommitmentConfirmed,
-			})
-			if err == nil && tx != nil && tx.Meta != nil && tx.Meta.LogMessages != nil {
-				for _, logLine := range tx.Meta.LogMessages {
-					if strings.Contains(logLine, "Instruction:") {
-						parts := strings.Split(logLine, "Instruction:")
-						if len(parts) == 2 {
-							memo = "Instruction:" + parts[1]
-							break
-						}
-					}
-				}
+
+	count := 0
+	for publications != nil || reorgs != nil {
+		select {
+		case pub, ok := <-publications:
+			if !ok {
+				publications = nil
+				continue
+			}
+			if limit > 0 && count >= limit {
+				continue
+			}
+			fmt.Printf("%-65s | %-10s | %-6d | %s\n",
+				pub.Signature.String(),
+				pub.Commitment,
+				pub.Slot,
+				summarizeEvents(pub.Events),
+			)
+			count++
+		case reorg, ok := <-reorgs:
+			if !ok {
+				reorgs = nil
+				continue
 			}
+			fmt.Printf("REORG detected: signature %s at slot %d\n", reorg.Signature, reorg.Slot)
 		}
-		
-		// Print the transaction info
-		fmt.Printf("%-65s | %-30s | %-10s | %s\n", 
-			sig.Signature.String(), 
-			timeStr, 
-			status,
-			memo,
-		)
-	}
-	
+	}
+
 	fmt.Println(strings.Repeat("-", 120))
-	fmt.Printf("Total: %d transactions\n\n", len(sigs))
-	
-	return newLastSignature, nil
-}
\ No newline at end of file
+	fmt.Printf("Total: %d transactions\n\n", count)
+}
+
+func summarizeEvents(decoded []events.DecodedEvent) string {
+	if len(decoded) == 0 {
+		return "-"
+	}
+
+	names := make([]string, len(decoded))
+	for i, e := range decoded {
+		names[i] = e.Name
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,88 @@
+package emit
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the top-level shape of a --config sinks.yaml file: a list of
+// sinks to fan out every event to.
+type Config struct {
+	Sinks []SinkConfig `yaml:"sinks"`
+}
+
+// SinkConfig describes one configured sink. Type selects which fields apply;
+// unused fields for a given type are ignored.
+type SinkConfig struct {
+	Type string `yaml:"type"` // "webhook", "nats", "kafka", or "file"
+
+	// webhook
+	URL       string `yaml:"url"`
+	Secret    string `yaml:"secret"`
+	QueueFile string `yaml:"queue_file"`
+
+	// nats
+	// (reuses URL above for the server address)
+
+	// kafka
+	Brokers []string `yaml:"brokers"`
+	Topic   string   `yaml:"topic"`
+
+	// file
+	Path     string `yaml:"path"`
+	MaxBytes int64  `yaml:"max_bytes"`
+}
+
+// LoadConfig reads a sinks.yaml file and builds a FanOut publishing to every
+// sink it describes. If any one sink fails to construct, every sink already
+// built is closed and the error is returned.
+func LoadConfig(path string) (*FanOut, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("emit: reading config %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("emit: parsing config %s: %w", path, err)
+	}
+
+	var built []Sink
+	for _, sc := range cfg.Sinks {
+		sink, err := buildSink(sc)
+		if err != nil {
+			for _, s := range built {
+				s.Close()
+			}
+			return nil, fmt.Errorf("emit: building %q sink: %w", sc.Type, err)
+		}
+		built = append(built, sink)
+	}
+
+	return NewFanOut(built...), nil
+}
+
+func buildSink(sc SinkConfig) (Sink, error) {
+	switch sc.Type {
+	case "webhook":
+		queueFile := sc.QueueFile
+		if queueFile == "" {
+			queueFile = "webhook_queue.jsonl"
+		}
+		return NewWebhookSink(sc.URL, []byte(sc.Secret), queueFile)
+	case "nats":
+		return NewNATSSink(sc.URL)
+	case "kafka":
+		return NewKafkaSink(sc.Brokers, sc.Topic), nil
+	case "file":
+		maxBytes := sc.MaxBytes
+		if maxBytes == 0 {
+			maxBytes = 100 * 1024 * 1024
+		}
+		return NewFileSink(sc.Path, maxBytes)
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", sc.Type)
+	}
+}
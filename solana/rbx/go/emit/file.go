@@ -0,0 +1,101 @@
+package emit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FileSink appends each event as one JSON line to path, rotating to
+// path.1, path.2, ... before a write would take the current file past
+// maxBytes.
+type FileSink struct {
+	path     string
+	maxBytes int64
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewFileSink opens (creating if necessary) a line-delimited JSON file at
+// path, rotating before a write would take it past maxBytes.
+func NewFileSink(path string, maxBytes int64) (*FileSink, error) {
+	f := &FileSink{path: path, maxBytes: maxBytes}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *FileSink) open() error {
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("emit: opening %s: %w", f.path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("emit: stat %s: %w", f.path, err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *FileSink) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("emit: marshaling file event: %w", err)
+	}
+	data = append(data, '\n')
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.maxBytes > 0 && f.size+int64(len(data)) > f.maxBytes {
+		if err := f.rotateLocked(); err != nil {
+			return err
+		}
+	}
+
+	n, err := f.file.Write(data)
+	f.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("emit: writing to %s: %w", f.path, err)
+	}
+	return nil
+}
+
+// rotateLocked closes the current file, shifts path.N -> path.N+1 (dropping
+// anything past a handful of generations), and opens a fresh file at path.
+func (f *FileSink) rotateLocked() error {
+	const keepGenerations = 5
+
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("emit: closing %s before rotation: %w", f.path, err)
+	}
+
+	for i := keepGenerations - 1; i >= 1; i-- {
+		src := fmt.Sprintf("%s.%d", f.path, i)
+		dst := fmt.Sprintf("%s.%d", f.path, i+1)
+		if _, err := os.Stat(src); err == nil {
+			os.Rename(src, dst)
+		}
+	}
+	if err := os.Rename(f.path, f.path+".1"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("emit: rotating %s: %w", f.path, err)
+	}
+
+	return f.open()
+}
+
+func (f *FileSink) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
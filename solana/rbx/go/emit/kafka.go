@@ -0,0 +1,80 @@
+package emit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// KafkaSink publishes events to a single Kafka topic, partitioned by the
+// trader's pubkey (when the decoded event has a Trader field) so all of one
+// trader's events land on the same partition and preserve order.
+type KafkaSink struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaSink returns a Sink producing to topic on brokers.
+func NewKafkaSink(brokers []string, topic string) *KafkaSink {
+	return &KafkaSink{
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(brokers...),
+			Topic:    topic,
+			Balancer: &kafka.Hash{},
+		},
+	}
+}
+
+func (k *KafkaSink) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("emit: marshaling Kafka event: %w", err)
+	}
+
+	msg := kafka.Message{Value: data}
+	if key := traderKey(event.Data); key != "" {
+		msg.Key = []byte(key)
+	}
+
+	if err := k.writer.WriteMessages(ctx, msg); err != nil {
+		return fmt.Errorf("emit: writing to Kafka topic %s: %w", k.writer.Topic, err)
+	}
+	return nil
+}
+
+func (k *KafkaSink) Close() error {
+	return k.writer.Close()
+}
+
+// traderKey pulls a "Trader" field's string form out of a decoded event via
+// reflection, so the sink doesn't need to know about every concrete event
+// struct. Events without a Trader field (or the raw fallback map) partition
+// round-robin instead.
+func traderKey(data any) string {
+	if data == nil {
+		return ""
+	}
+
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+
+	field := v.FieldByName("Trader")
+	if !field.IsValid() {
+		return ""
+	}
+
+	if stringer, ok := field.Interface().(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return fmt.Sprintf("%v", field.Interface())
+}
@@ -0,0 +1,53 @@
+package emit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSSink publishes events to a JetStream stream, subject-keyed by
+// "<programID>.<eventName>" so consumers can subscribe to a single event
+// type or wildcard across a program.
+type NATSSink struct {
+	conn *nats.Conn
+	js   nats.JetStreamContext
+}
+
+// NewNATSSink connects to url and returns a Sink that publishes through
+// JetStream.
+func NewNATSSink(url string) (*NATSSink, error) {
+	conn, err := nats.Connect(url)
+	if err != nil {
+		return nil, fmt.Errorf("emit: connecting to NATS at %s: %w", url, err)
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("emit: opening JetStream context: %w", err)
+	}
+
+	return &NATSSink{conn: conn, js: js}, nil
+}
+
+func (n *NATSSink) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("emit: marshaling NATS event: %w", err)
+	}
+
+	subject := fmt.Sprintf("%s.%s", event.ProgramID, event.Name)
+	_, err = n.js.Publish(subject, data, nats.Context(ctx))
+	if err != nil {
+		return fmt.Errorf("emit: publishing to NATS subject %s: %w", subject, err)
+	}
+	return nil
+}
+
+func (n *NATSSink) Close() error {
+	n.conn.Close()
+	return nil
+}
@@ -0,0 +1,75 @@
+// Package emit turns decoded program events into outbound messages a real
+// indexer pipeline can consume, instead of just a log.Printf. Sinks are
+// independent and failure-isolated: a dead webhook endpoint doesn't stop the
+// Kafka or file sinks from receiving the same event.
+package emit
+
+import (
+	"context"
+	"log"
+)
+
+// Event is the outbound envelope every sink receives, decoupled from the
+// watcher package so sinks don't need to import solana-go types directly.
+type Event struct {
+	ProgramID  string `json:"program_id"`
+	Slot       uint64 `json:"slot"`
+	Signature  string `json:"signature"`
+	Commitment string `json:"commitment"`
+	Name       string `json:"event"`
+	Data       any    `json:"data"`
+}
+
+// Sink publishes decoded events somewhere downstream - a webhook, a message
+// broker, a file. Implementations must be safe for concurrent use.
+type Sink interface {
+	Publish(ctx context.Context, event Event) error
+	Close() error
+}
+
+// FanOut publishes to every configured Sink and isolates failures: an error
+// from one sink is logged but doesn't stop the others from receiving the
+// event. Every failure is logged; only the first is returned to the caller. It itself
+// implements Sink, so it can be nested or swapped in wherever a single Sink
+// is expected.
+type FanOut struct {
+	sinks []Sink
+}
+
+// NewFanOut returns a Sink that publishes to every one of sinks.
+func NewFanOut(sinks ...Sink) *FanOut {
+	return &FanOut{sinks: sinks}
+}
+
+func (f *FanOut) Publish(ctx context.Context, event Event) error {
+	var firstErr error
+	for _, sink := range f.sinks {
+		if err := sink.Publish(ctx, event); err != nil {
+			log.Printf("emit: sink failed, continuing with the rest: %v", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// NopSink discards every event. It's the default Sink when no --config is
+// given, so callers can always call Publish without a nil check.
+type NopSink struct{}
+
+func (NopSink) Publish(ctx context.Context, event Event) error { return nil }
+func (NopSink) Close() error                                   { return nil }
+
+func (f *FanOut) Close() error {
+	var firstErr error
+	for _, sink := range f.sinks {
+		if err := sink.Close(); err != nil {
+			log.Printf("emit: sink close failed: %v", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,248 @@
+package emit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	webhookMaxBackoff  = 30 * time.Second
+	webhookInitBackoff = 500 * time.Millisecond
+	// webhookMaxQueued bounds the on-disk retry queue; once it's full the
+	// oldest queued event is dropped to make room rather than growing
+	// unbounded during a long downstream outage.
+	webhookMaxQueued = 10_000
+)
+
+// WebhookSink POSTs each event as JSON to a URL, signing the body with
+// HMAC-SHA256 so the receiver can verify it came from us. Deliveries that
+// fail are retried with exponential backoff from a bounded on-disk
+// queue, so events survive both transient failures and a process restart.
+type WebhookSink struct {
+	url    string
+	secret []byte
+	client *http.Client
+
+	queuePath string
+	mu        sync.Mutex
+
+	stop    chan struct{}
+	drainWg sync.WaitGroup
+}
+
+// NewWebhookSink starts a WebhookSink posting to url, signing with secret,
+// and backing its retry queue with the file at queuePath.
+func NewWebhookSink(url string, secret []byte, queuePath string) (*WebhookSink, error) {
+	w := &WebhookSink{
+		url:       url,
+		secret:    secret,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		queuePath: queuePath,
+		stop:      make(chan struct{}),
+	}
+
+	if err := w.enforceQueueBound(); err != nil {
+		return nil, err
+	}
+
+	w.drainWg.Add(1)
+	go w.drainLoop()
+
+	return w, nil
+}
+
+// Publish enqueues event for delivery; the actual HTTP POST happens
+// asynchronously on the drain loop so a slow or down endpoint never blocks
+// the caller.
+func (w *WebhookSink) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("emit: marshaling webhook event: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	f, err := os.OpenFile(w.queuePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("emit: opening webhook queue: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("emit: appending to webhook queue: %w", err)
+	}
+
+	return w.enforceQueueBoundLocked()
+}
+
+func (w *WebhookSink) Close() error {
+	close(w.stop)
+	w.drainWg.Wait()
+	return nil
+}
+
+// drainLoop repeatedly attempts to deliver the oldest queued event, backing
+// off on failure and rewriting the queue file once an event is confirmed
+// delivered.
+func (w *WebhookSink) drainLoop() {
+	defer w.drainWg.Done()
+
+	backoff := webhookInitBackoff
+	for {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		delivered, err := w.deliverOldest()
+		if err != nil {
+			log.Printf("emit: webhook queue drain error: %v", err)
+		}
+
+		if delivered {
+			backoff = webhookInitBackoff
+			continue // more may be queued; try again immediately
+		}
+
+		select {
+		case <-w.stop:
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > webhookMaxBackoff {
+			backoff = webhookMaxBackoff
+		}
+	}
+}
+
+// deliverOldest POSTs the oldest queued event and then removes it from the
+// queue. It returns delivered=true only once the POST has succeeded; an empty
+// queue or a failed POST returns false. The queue lock is not held during the
+// POST, so Publish is never blocked by a slow endpoint.
+func (w *WebhookSink) deliverOldest() (delivered bool, err error) {
+	w.mu.Lock()
+	lines, err := w.readQueueLocked()
+	w.mu.Unlock()
+	if err != nil {
+		return false, err
+	}
+	if len(lines) == 0 {
+		return false, nil
+	}
+
+	oldest := lines[0]
+	if postErr := w.post(oldest); postErr != nil {
+		return false, postErr
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// Publish may have appended (and enforceQueueBoundLocked may have dropped
+	// the head) while the POST was in flight, so re-read before removing it.
+	if lines, err = w.readQueueLocked(); err != nil {
+		return true, err
+	}
+	if len(lines) > 0 && bytes.Equal(lines[0], oldest) {
+		lines = lines[1:]
+	}
+	return true, w.writeQueueLocked(lines)
+}
+
+func (w *WebhookSink) post(body []byte) error {
+	sig := hmac.New(sha256.New, w.secret)
+	sig.Write(body)
+	signature := hex.EncodeToString(sig.Sum(nil))
+
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-RBX-Signature", signature)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (w *WebhookSink) readQueueLocked() ([][]byte, error) {
+	f, err := os.Open(w.queuePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines [][]byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := append([]byte(nil), scanner.Bytes()...)
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+func (w *WebhookSink) writeQueueLocked(lines [][]byte) error {
+	tmp := w.queuePath + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if _, err := f.Write(append(line, '\n')); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, w.queuePath)
+}
+
+func (w *WebhookSink) enforceQueueBound() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.enforceQueueBoundLocked()
+}
+
+// enforceQueueBoundLocked drops the oldest queued entries once the queue
+// exceeds webhookMaxQueued, rather than letting a long outage grow the
+// on-disk queue without limit.
+func (w *WebhookSink) enforceQueueBoundLocked() error {
+	lines, err := w.readQueueLocked()
+	if err != nil {
+		return err
+	}
+	if len(lines) <= webhookMaxQueued {
+		return nil
+	}
+
+	dropped := len(lines) - webhookMaxQueued
+	log.Printf("emit: webhook queue exceeded %d entries, dropping oldest %d", webhookMaxQueued, dropped)
+	return w.writeQueueLocked(lines[dropped:])
+}
@@ -0,0 +1,101 @@
+package events
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/near/borsh-go"
+)
+
+// logPrefix is how the Solana runtime tags an `emit!`-ed Anchor event in the
+// transaction logs.
+const logPrefix = "Program data: "
+
+// ErrNotEventLog is returned by Decode when the log line isn't a
+// "Program data: " line at all, as opposed to one that failed to decode.
+var ErrNotEventLog = errors.New("events: log line is not a program data entry")
+
+// DecodedEvent is one Anchor event pulled out of a transaction's logs.
+type DecodedEvent struct {
+	Name     string
+	Value    any
+	LogIndex int
+}
+
+// Decode extracts and decodes a single Anchor event from a raw log line. If
+// the event's name was registered via Register, Value is a pointer to a
+// fresh instance of that Go type populated via Borsh. Otherwise Value is a
+// map[string]any with the single "raw" key holding the undecoded event
+// bytes, since Borsh has no schema to decode into without a registered type.
+func (r *Registry) Decode(logLine string) (name string, value any, err error) {
+	if !strings.HasPrefix(logLine, logPrefix) {
+		return "", nil, ErrNotEventLog
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(logLine, logPrefix))
+	if err != nil {
+		return "", nil, fmt.Errorf("events: base64 decode: %w", err)
+	}
+
+	if len(data) < 8 {
+		return "", nil, fmt.Errorf("events: data too short for a discriminator, got %d bytes", len(data))
+	}
+
+	var disc [8]byte
+	copy(disc[:], data[:8])
+
+	s, ok := r.lookup(disc)
+	if !ok {
+		return "", nil, fmt.Errorf("events: unknown discriminator %x", disc)
+	}
+
+	if s.goType == nil {
+		return s.name, map[string]any{"raw": data[8:]}, nil
+	}
+
+	v := reflectNewAsPointer(s)
+	if err := borsh.Deserialize(v, data[8:]); err != nil {
+		return s.name, nil, fmt.Errorf("events: borsh decode %s: %w", s.name, err)
+	}
+
+	return s.name, v, nil
+}
+
+// DecodeLogs decodes every Anchor event found in logs, silently skipping
+// lines that aren't event data or fail to decode; per-line errors are not
+// returned (call Decode directly to see them). Useful when the logs come from somewhere
+// other than a fetched transaction, e.g. a live logsSubscribe notification.
+func (r *Registry) DecodeLogs(logs []string) []DecodedEvent {
+	var out []DecodedEvent
+	for i, logLine := range logs {
+		name, value, err := r.Decode(logLine)
+		if err != nil {
+			continue
+		}
+		out = append(out, DecodedEvent{Name: name, Value: value, LogIndex: i})
+	}
+	return out
+}
+
+// DecodeTx decodes every Anchor event found in tx's log messages, silently
+// skipping lines that aren't event data or fail to decode; per-line errors
+// are not returned (call Decode directly to see them).
+func (r *Registry) DecodeTx(tx *rpc.GetTransactionResult) []DecodedEvent {
+	if tx == nil || tx.Meta == nil {
+		return nil
+	}
+
+	var out []DecodedEvent
+	for i, logLine := range tx.Meta.LogMessages {
+		name, value, err := r.Decode(logLine)
+		if err != nil {
+			continue
+		}
+		out = append(out, DecodedEvent{Name: name, Value: value, LogIndex: i})
+	}
+
+	return out
+}
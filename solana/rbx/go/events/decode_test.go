@@ -0,0 +1,122 @@
+package events
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/near/borsh-go"
+)
+
+func testRegistry(t *testing.T) *Registry {
+	t.Helper()
+
+	r := NewRegistry()
+	err := r.LoadIDL(&IDL{
+		Name: "rabbitx_vault",
+		Events: []IDLEvent{
+			{Name: "DepositEvent"},
+			{Name: "WithdrawalEvent"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("LoadIDL: %v", err)
+	}
+	if err := r.Register("DepositEvent", DepositEvent{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return r
+}
+
+func encodeEventLog(t *testing.T, name string, value any) string {
+	t.Helper()
+
+	payload, err := borsh.Serialize(value)
+	if err != nil {
+		t.Fatalf("borsh.Serialize: %v", err)
+	}
+
+	disc := eventDiscriminator(name)
+	data := append(disc[:], payload...)
+	return logPrefix + base64.StdEncoding.EncodeToString(data)
+}
+
+func TestDecodeRegisteredEventType(t *testing.T) {
+	r := testRegistry(t)
+
+	want := DepositEvent{
+		ID:     42,
+		Trader: solana.MustPublicKeyFromBase58("11111111111111111111111111111111"),
+		Amount: 1_000_000,
+		Token:  solana.MustPublicKeyFromBase58("11111111111111111111111111111111"),
+	}
+	logLine := encodeEventLog(t, "DepositEvent", want)
+
+	name, value, err := r.Decode(logLine)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if name != "DepositEvent" {
+		t.Errorf("name = %q, want DepositEvent", name)
+	}
+
+	got, ok := value.(*DepositEvent)
+	if !ok {
+		t.Fatalf("value has type %T, want *DepositEvent", value)
+	}
+	if *got != want {
+		t.Errorf("decoded %+v, want %+v", *got, want)
+	}
+}
+
+func TestDecodeUnregisteredEventReturnsRawBytes(t *testing.T) {
+	r := testRegistry(t)
+
+	want := WithdrawalEvent{ID: 7, Amount: 5}
+	logLine := encodeEventLog(t, "WithdrawalEvent", want)
+
+	name, value, err := r.Decode(logLine)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if name != "WithdrawalEvent" {
+		t.Errorf("name = %q, want WithdrawalEvent", name)
+	}
+
+	raw, ok := value.(map[string]any)
+	if !ok {
+		t.Fatalf("value has type %T, want map[string]any", value)
+	}
+	if _, ok := raw["raw"].([]byte); !ok {
+		t.Errorf("expected a \"raw\" []byte key, got %+v", raw)
+	}
+}
+
+func TestDecodeUnknownDiscriminatorErrors(t *testing.T) {
+	r := testRegistry(t)
+
+	logLine := encodeEventLog(t, "SomeOtherEvent", struct{ X uint64 }{X: 1})
+
+	if _, _, err := r.Decode(logLine); err == nil {
+		t.Fatal("expected an error for an unregistered event name, got nil")
+	}
+}
+
+func TestDecodeNonEventLogLine(t *testing.T) {
+	r := testRegistry(t)
+
+	_, _, err := r.Decode("Program log: hello")
+	if !errors.Is(err, ErrNotEventLog) {
+		t.Errorf("err = %v, want ErrNotEventLog", err)
+	}
+}
+
+func TestDecodeTooShortForDiscriminator(t *testing.T) {
+	r := testRegistry(t)
+
+	logLine := logPrefix + base64.StdEncoding.EncodeToString([]byte{1, 2, 3})
+	if _, _, err := r.Decode(logLine); err == nil {
+		t.Fatal("expected an error for data shorter than a discriminator, got nil")
+	}
+}
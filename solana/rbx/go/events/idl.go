@@ -0,0 +1,52 @@
+package events
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// IDL is the subset of an Anchor IDL we need to build a discriminator table.
+// Anchor IDLs carry a lot more (instructions, accounts, types) but event
+// decoding only cares about the `events` section.
+type IDL struct {
+	Name   string     `json:"name"`
+	Events []IDLEvent `json:"events"`
+}
+
+type IDLEvent struct {
+	Name   string          `json:"name"`
+	Fields []IDLEventField `json:"fields"`
+}
+
+type IDLEventField struct {
+	Name  string          `json:"name"`
+	Type  json.RawMessage `json:"type"`
+	Index bool            `json:"index"`
+}
+
+// LoadIDLFile reads and parses an Anchor IDL JSON file from disk.
+func LoadIDLFile(path string) (*IDL, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading IDL file %s: %w", path, err)
+	}
+
+	var idl IDL
+	if err := json.Unmarshal(data, &idl); err != nil {
+		return nil, fmt.Errorf("parsing IDL file %s: %w", path, err)
+	}
+
+	return &idl, nil
+}
+
+// eventDiscriminator computes the 8-byte Anchor event discriminator, which is
+// the first 8 bytes of SHA256("event:<Name>").
+func eventDiscriminator(name string) [8]byte {
+	sum := sha256.Sum256([]byte("event:" + name))
+
+	var disc [8]byte
+	copy(disc[:], sum[:8])
+	return disc
+}
@@ -0,0 +1,46 @@
+package events
+
+import "github.com/gagliardetto/solana-go"
+
+// DepositEvent mirrors the `DepositEvent` Anchor event; field order must
+// match the IDL so Borsh decodes it correctly.
+type DepositEvent struct {
+	ID     uint64
+	Trader solana.PublicKey
+	Amount uint64
+	Token  solana.PublicKey
+}
+
+// WithdrawalEvent mirrors the `WithdrawalEvent` Anchor event; field order
+// must match the IDL so Borsh decodes it correctly.
+type WithdrawalEvent struct {
+	ID     uint64
+	Trader solana.PublicKey
+	Amount uint64
+	Token  solana.PublicKey
+}
+
+// LoadRabbitXRegistry builds the event decoder registry from the IDL at
+// idlPath and attaches the Go structs the RabbitX tools know how to display
+// nicely, so the event listener, event poller and backfill commands decode
+// DepositEvent/WithdrawalEvent the same way. transaction-lister builds its
+// own registry without these structs, since it only prints event names.
+func LoadRabbitXRegistry(idlPath string) (*Registry, error) {
+	idl, err := LoadIDLFile(idlPath)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := NewRegistry()
+	if err := registry.LoadIDL(idl); err != nil {
+		return nil, err
+	}
+	if err := registry.Register("DepositEvent", DepositEvent{}); err != nil {
+		return nil, err
+	}
+	if err := registry.Register("WithdrawalEvent", WithdrawalEvent{}); err != nil {
+		return nil, err
+	}
+
+	return registry, nil
+}
@@ -0,0 +1,86 @@
+// Package events decodes Anchor program events from transaction logs. It
+// replaces the hand-rolled, fixed-offset byte parsing the RabbitX tools used
+// to do for DepositEvent/WithdrawalEvent with a generic, IDL-driven decoder,
+// following the approach Wormhole's Solana watcher uses for its own Anchor
+// programs.
+package events
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// schema describes one registered Anchor event: its discriminator and,
+// optionally, the Go struct that should receive its decoded fields.
+type schema struct {
+	name          string
+	discriminator [8]byte
+	goType        reflect.Type
+}
+
+// Registry maps Anchor event discriminators to schemas loaded from an IDL.
+// A zero-value Registry is not usable; create one with NewRegistry.
+type Registry struct {
+	mu              sync.RWMutex
+	byName          map[string]*schema
+	byDiscriminator map[[8]byte]*schema
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		byName:          make(map[string]*schema),
+		byDiscriminator: make(map[[8]byte]*schema),
+	}
+}
+
+// LoadIDL registers every event declared in idl, computing each one's
+// discriminator from its name. It does not attach Go structs; call Register
+// for each event name you want decoded into a concrete type.
+func (r *Registry) LoadIDL(idl *IDL) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, e := range idl.Events {
+		s := &schema{
+			name:          e.Name,
+			discriminator: eventDiscriminator(e.Name),
+		}
+		r.byName[e.Name] = s
+		r.byDiscriminator[s.discriminator] = s
+	}
+
+	return nil
+}
+
+// Register associates proto's type with an already-loaded event name, so
+// future Decode calls for that discriminator Borsh-decode into a fresh
+// instance of proto's type instead of returning raw bytes. proto is only
+// used for its type; its value is discarded.
+func (r *Registry) Register(name string, proto any) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.byName[name]
+	if !ok {
+		return fmt.Errorf("events: register %q: no such event in loaded IDL", name)
+	}
+
+	s.goType = reflect.TypeOf(proto)
+	return nil
+}
+
+func (r *Registry) lookup(disc [8]byte) (*schema, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.byDiscriminator[disc]
+	return s, ok
+}
+
+// reflectNewAsPointer allocates a zero value of s.goType and returns a
+// pointer to it, suitable for passing to borsh.Deserialize.
+func reflectNewAsPointer(s *schema) any {
+	return reflect.New(s.goType).Interface()
+}
@@ -0,0 +1,54 @@
+// Package health serves a /healthz liveness endpoint alongside the
+// Prometheus /metrics endpoint for long-running watcher processes.
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Status tracks whether a process's live data feed (e.g. a WebSocket
+// subscription) is currently connected, so /healthz can report it without
+// reaching into the feed's internals.
+type Status struct {
+	connected int32
+}
+
+// NewStatus returns a Status reporting disconnected until SetConnected(true)
+// is called.
+func NewStatus() *Status {
+	return &Status{}
+}
+
+func (s *Status) SetConnected(connected bool) {
+	var v int32
+	if connected {
+		v = 1
+	}
+	atomic.StoreInt32(&s.connected, v)
+}
+
+func (s *Status) Connected() bool {
+	return atomic.LoadInt32(&s.connected) == 1
+}
+
+// Serve starts an HTTP server on addr exposing /healthz (200 when connected,
+// 503 otherwise) and /metrics (Prometheus exposition format). It blocks
+// until the server stops, so callers should run it in its own goroutine.
+func Serve(addr string, status *Status) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		connected := status.Connected()
+		w.Header().Set("Content-Type", "application/json")
+		if !connected {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"connected": connected})
+	})
+	mux.Handle("/metrics", promhttp.Handler())
+
+	return http.ListenAndServe(addr, mux)
+}
@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+)
+
+var (
+	signaturesBucket = []byte("signatures") // sig -> slot (big-endian u64)
+	bySlotBucket     = []byte("by_slot")    // slot(big-endian u64) + sig -> nil, for range-pruning
+	metaBucket       = []byte("meta")       // "last_slot:<commitment>" -> slot (big-endian u64)
+)
+
+// lastSlotMetaKey namespaces the last-slot watermark by commitment level, so
+// a confirmed signature reaching a slot doesn't fast-forward the finalized
+// watermark past slots finalized processing never actually saw.
+func lastSlotMetaKey(commitment string) []byte {
+	return []byte("last_slot:" + commitment)
+}
+
+// BoltStore is the default Store backend: a single BoltDB file keyed by
+// signature, with a secondary slot-ordered index so Prune can reclaim old
+// entries without scanning every signature.
+type BoltStore struct {
+	db *bbolt.DB
+}
+
+// NewBoltStore opens (creating if necessary) a BoltDB-backed Store at path.
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bbolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("storage: opening %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, bucket := range [][]byte{signaturesBucket, bySlotBucket, metaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("storage: initializing buckets in %s: %w", path, err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+func (s *BoltStore) Seen(sig string) (bool, error) {
+	var seen bool
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		seen = tx.Bucket(signaturesBucket).Get([]byte(sig)) != nil
+		return nil
+	})
+	return seen, err
+}
+
+func (s *BoltStore) Mark(sig string, slot uint64, commitment string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		slotBytes := encodeSlot(slot)
+
+		if err := tx.Bucket(signaturesBucket).Put([]byte(sig), slotBytes); err != nil {
+			return err
+		}
+		if err := tx.Bucket(bySlotBucket).Put(bySlotKey(slot, sig), nil); err != nil {
+			return err
+		}
+
+		meta := tx.Bucket(metaBucket)
+		key := lastSlotMetaKey(commitment)
+		if existing := meta.Get(key); existing == nil || binary.BigEndian.Uint64(existing) < slot {
+			if err := meta.Put(key, slotBytes); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) LastSlot(commitment string) (uint64, bool, error) {
+	var slot uint64
+	var ok bool
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(metaBucket).Get(lastSlotMetaKey(commitment))
+		if v == nil {
+			return nil
+		}
+		slot = binary.BigEndian.Uint64(v)
+		ok = true
+		return nil
+	})
+	return slot, ok, err
+}
+
+func (s *BoltStore) Prune(olderThanSlot uint64) (int, error) {
+	var pruned int
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		bySlot := tx.Bucket(bySlotBucket)
+		signatures := tx.Bucket(signaturesBucket)
+
+		c := bySlot.Cursor()
+		var toDelete [][]byte
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			slot := binary.BigEndian.Uint64(k[:8])
+			if slot >= olderThanSlot {
+				// Keys are ordered by slot, so nothing after this is older either.
+				break
+			}
+			toDelete = append(toDelete, append([]byte(nil), k...))
+		}
+
+		for _, k := range toDelete {
+			sig := k[8:]
+			if err := signatures.Delete(sig); err != nil {
+				return err
+			}
+			if err := bySlot.Delete(k); err != nil {
+				return err
+			}
+			pruned++
+		}
+		return nil
+	})
+	return pruned, err
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// CursorStats reports the on-disk cursor state for the `inspect` command.
+type CursorStats struct {
+	SignatureCount    int
+	LastConfirmedSlot uint64
+	HasConfirmedSlot  bool
+	LastFinalizedSlot uint64
+	HasFinalizedSlot  bool
+}
+
+// Stats reports the number of signatures currently tracked and the highest
+// marked slot for each commitment level. It isn't part of the Store
+// interface since it's specific to debugging the on-disk state.
+func (s *BoltStore) Stats() (CursorStats, error) {
+	var st CursorStats
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		st.SignatureCount = tx.Bucket(signaturesBucket).Stats().KeyN
+
+		meta := tx.Bucket(metaBucket)
+		if v := meta.Get(lastSlotMetaKey("confirmed")); v != nil {
+			st.LastConfirmedSlot = binary.BigEndian.Uint64(v)
+			st.HasConfirmedSlot = true
+		}
+		if v := meta.Get(lastSlotMetaKey("finalized")); v != nil {
+			st.LastFinalizedSlot = binary.BigEndian.Uint64(v)
+			st.HasFinalizedSlot = true
+		}
+		return nil
+	})
+	return st, err
+}
+
+func encodeSlot(slot uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, slot)
+	return b
+}
+
+func bySlotKey(slot uint64, sig string) []byte {
+	key := make([]byte, 8+len(sig))
+	binary.BigEndian.PutUint64(key, slot)
+	copy(key[8:], sig)
+	return key
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func newTestStore(t *testing.T) *BoltStore {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cursor.db")
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestMarkAndSeen(t *testing.T) {
+	store := newTestStore(t)
+
+	seen, err := store.Seen("sig1")
+	if err != nil {
+		t.Fatalf("Seen: %v", err)
+	}
+	if seen {
+		t.Fatalf("expected sig1 to be unseen before Mark")
+	}
+
+	if err := store.Mark("sig1", 100, "confirmed"); err != nil {
+		t.Fatalf("Mark: %v", err)
+	}
+
+	seen, err = store.Seen("sig1")
+	if err != nil || !seen {
+		t.Fatalf("expected sig1 to be seen after Mark, got seen=%v err=%v", seen, err)
+	}
+}
+
+// TestLastSlotTracksCommitmentsIndependently pins the watermark-per-commitment
+// behavior: a Mark at one commitment level must never move the watermark for
+// another, since confirmed always reaches a given slot before finalized does.
+func TestLastSlotTracksCommitmentsIndependently(t *testing.T) {
+	store := newTestStore(t)
+
+	if err := store.Mark("sig-confirmed", 200, "confirmed"); err != nil {
+		t.Fatalf("Mark confirmed: %v", err)
+	}
+	if err := store.Mark("sig-finalized", 150, "finalized"); err != nil {
+		t.Fatalf("Mark finalized: %v", err)
+	}
+
+	if confirmed, ok, err := store.LastSlot("confirmed"); err != nil || !ok || confirmed != 200 {
+		t.Fatalf("LastSlot(confirmed) = (%d, %v, %v), want (200, true, nil)", confirmed, ok, err)
+	}
+	if finalized, ok, err := store.LastSlot("finalized"); err != nil || !ok || finalized != 150 {
+		t.Fatalf("LastSlot(finalized) = (%d, %v, %v), want (150, true, nil)", finalized, ok, err)
+	}
+
+	if err := store.Mark("sig-confirmed-2", 500, "confirmed"); err != nil {
+		t.Fatalf("Mark confirmed 2: %v", err)
+	}
+	if finalized, _, err := store.LastSlot("finalized"); err != nil || finalized != 150 {
+		t.Fatalf("finalized watermark moved after a confirmed-only Mark: got %d, want 150", finalized)
+	}
+}
+
+func TestLastSlotUnmarkedCommitmentReturnsFalse(t *testing.T) {
+	store := newTestStore(t)
+
+	if _, ok, err := store.LastSlot("confirmed"); err != nil || ok {
+		t.Fatalf("expected no confirmed watermark yet, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestPruneRemovesOnlyOlderSlotsFromBothIndexes(t *testing.T) {
+	store := newTestStore(t)
+
+	for i, slot := range []uint64{10, 20, 30, 40} {
+		sig := fmt.Sprintf("sig%d", i)
+		if err := store.Mark(sig, slot, "finalized"); err != nil {
+			t.Fatalf("Mark: %v", err)
+		}
+	}
+
+	pruned, err := store.Prune(30)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if pruned != 2 {
+		t.Fatalf("Prune removed %d entries, want 2", pruned)
+	}
+
+	for sig, wantSeen := range map[string]bool{
+		"sig0": false, // slot 10, pruned
+		"sig1": false, // slot 20, pruned
+		"sig2": true,  // slot 30, kept
+		"sig3": true,  // slot 40, kept
+	} {
+		seen, err := store.Seen(sig)
+		if err != nil {
+			t.Fatalf("Seen(%s): %v", sig, err)
+		}
+		if seen != wantSeen {
+			t.Errorf("Seen(%s) = %v, want %v", sig, seen, wantSeen)
+		}
+	}
+
+	stats, err := store.Stats()
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.SignatureCount != 2 {
+		t.Errorf("SignatureCount = %d, want 2", stats.SignatureCount)
+	}
+}
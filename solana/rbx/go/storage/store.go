@@ -0,0 +1,33 @@
+// Package storage persists which transaction signatures the watcher has
+// already processed, so a restart resumes from where it left off instead of
+// re-initializing from whatever GetSignaturesForAddress happens to return at
+// that moment (which silently drops events that landed between runs).
+package storage
+
+// Store is the persistence interface the three monitoring tools use to
+// de-duplicate signatures and track progress across restarts. Implementations
+// must be safe for concurrent use.
+type Store interface {
+	// Seen reports whether sig has already been marked processed.
+	Seen(sig string) (bool, error)
+
+	// Mark records sig as processed at slot for the given commitment level
+	// (e.g. "confirmed" or "finalized"), so future Seen calls for it return
+	// true and Prune can later reclaim it. The commitment-specific watermark
+	// returned by LastSlot only advances for that same commitment, since
+	// confirmed always reaches a given slot before finalized does and
+	// conflating the two would let a restart fast-forward the finalized
+	// cursor past slots it never actually processed at that level.
+	Mark(sig string, slot uint64, commitment string) error
+
+	// LastSlot returns the highest slot Mark has recorded for commitment so
+	// far, and false if nothing has been marked at that level yet.
+	LastSlot(commitment string) (uint64, bool, error)
+
+	// Prune removes every signature marked at a slot older than
+	// olderThanSlot, returning how many were removed.
+	Prune(olderThanSlot uint64) (int, error)
+
+	// Close releases any resources held by the store.
+	Close() error
+}
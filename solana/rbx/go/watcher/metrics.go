@@ -0,0 +1,37 @@
+package watcher
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics mirror the shape used by Wormhole's Solana watcher, so the same
+// Grafana dashboards/alerts can be pointed at this tool with only a job name
+// change.
+var (
+	RPCErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rpc_errors_total",
+		Help: "Total number of RPC errors by method",
+	}, []string{"method"})
+
+	EventsConfirmedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "events_confirmed_total",
+		Help: "Total number of program events observed at confirmed commitment",
+	})
+
+	EventsFinalizedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "events_finalized_total",
+		Help: "Total number of program events observed at finalized commitment",
+	})
+
+	CurrentSlot = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "current_slot",
+		Help: "Most recently observed slot by commitment level",
+	}, []string{"commitment"})
+
+	WSReconnectsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ws_reconnects_total",
+		Help: "Total number of times the live WebSocket subscription had to reconnect",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(RPCErrorsTotal, EventsConfirmedTotal, EventsFinalizedTotal, CurrentSlot, WSReconnectsTotal)
+}
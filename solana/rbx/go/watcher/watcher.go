@@ -0,0 +1,283 @@
+// Package watcher replaces the three independent polling loops in
+// transaction-lister/event-listener/event-poller with a single slot-based
+// watcher modeled on Wormhole's Solana watcher: it tracks confirmed and
+// finalized slots separately, walks blocks rather than relying on
+// GetSignaturesForAddress (which can miss transactions between polls), and
+// flags confirmed signatures that disappear by the time their slot
+// finalizes as reorgs.
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/alex-sumner/solana-rabbitx-contract/solana/rbx/go/events"
+)
+
+// MessagePublication is one program-relevant transaction, decoded and ready
+// for a downstream consumer. The same signature is published twice: once
+// when it reaches CommitmentConfirmed and again when it reaches
+// CommitmentFinalized, so consumers can pick their own safety/latency
+// tradeoff.
+type MessagePublication struct {
+	Slot       uint64
+	Signature  solana.Signature
+	Commitment rpc.CommitmentType
+	Events     []events.DecodedEvent
+}
+
+// Reorged is emitted when a signature observed at CommitmentConfirmed is no
+// longer present in its slot once that slot finalizes.
+type Reorged struct {
+	Slot      uint64
+	Signature solana.Signature
+}
+
+// maxSupportedTxVersion lets GetBlock return version-0 (address lookup
+// table) transactions alongside legacy ones.
+var maxSupportedTxVersion uint64 = 0
+
+// Watcher walks Solana blocks for a single program ID and emits decoded
+// events at both confirmed and finalized commitment.
+type Watcher struct {
+	client    *rpc.Client
+	programID solana.PublicKey
+	registry  *events.Registry
+
+	lastConfirmedSlot uint64
+	lastFinalizedSlot uint64
+
+	// confirmedSigs records, per slot, the signatures we published at
+	// confirmed commitment. When that slot finalizes we diff against it to
+	// detect a reorg, then drop the entry.
+	confirmedSigs map[uint64]map[solana.Signature]bool
+}
+
+// New returns a Watcher for programID, decoding events with registry.
+func New(client *rpc.Client, programID solana.PublicKey, registry *events.Registry) *Watcher {
+	return &Watcher{
+		client:        client,
+		programID:     programID,
+		registry:      registry,
+		confirmedSigs: make(map[uint64]map[solana.Signature]bool),
+	}
+}
+
+// Resume sets the confirmed and finalized cursors independently, so the next
+// poll at each commitment level walks from its own watermark+1 instead of
+// treating its first GetSlot as a fresh baseline. Confirmed commitment
+// always reaches a given slot before finalized does, so the two watermarks
+// must be tracked and restored separately - collapsing them to one would
+// fast-forward the finalized cursor past slots that were confirmed but never
+// actually processed at finalized commitment, permanently skipping their
+// reorg check. Callers restoring from a persisted cursor should call this
+// before Run/PollOnce.
+func (w *Watcher) Resume(confirmedSlot, finalizedSlot uint64) {
+	w.lastConfirmedSlot = confirmedSlot
+	w.lastFinalizedSlot = finalizedSlot
+}
+
+// Run polls both commitment levels on pollInterval until ctx is cancelled,
+// sending decoded transactions to publications and reorg notifications to
+// reorgs. Either channel may be nil if the caller doesn't care.
+func (w *Watcher) Run(ctx context.Context, pollInterval time.Duration, publications chan<- MessagePublication, reorgs chan<- Reorged) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			w.PollOnce(ctx, publications, reorgs)
+		}
+	}
+}
+
+// PollOnce runs a single confirmed-then-finalized round, suitable for
+// one-shot CLI invocations as well as the Run loop above.
+func (w *Watcher) PollOnce(ctx context.Context, publications chan<- MessagePublication, reorgs chan<- Reorged) {
+	if err := w.pollCommitment(ctx, rpc.CommitmentConfirmed, publications, reorgs); err != nil {
+		log.Printf("watcher: confirmed poll failed: %v", err)
+	}
+	if err := w.pollCommitment(ctx, rpc.CommitmentFinalized, publications, reorgs); err != nil {
+		log.Printf("watcher: finalized poll failed: %v", err)
+	}
+}
+
+func (w *Watcher) pollCommitment(ctx context.Context, commitment rpc.CommitmentType, publications chan<- MessagePublication, reorgs chan<- Reorged) error {
+	slot, err := w.client.GetSlot(ctx, commitment)
+	if err != nil {
+		RPCErrorsTotal.WithLabelValues("GetSlot").Inc()
+		return fmt.Errorf("watcher: GetSlot(%s): %w", commitment, err)
+	}
+	CurrentSlot.WithLabelValues(string(commitment)).Set(float64(slot))
+
+	last := w.lastSlotFor(commitment)
+	if *last == 0 {
+		// First observation: just establish the baseline, don't replay history.
+		*last = slot
+		return nil
+	}
+	if slot <= *last {
+		return nil
+	}
+
+	from, to := *last+1, slot
+	seen, err := w.walkSlots(ctx, from, to, commitment, publications)
+	if err != nil {
+		return err
+	}
+
+	if commitment == rpc.CommitmentFinalized {
+		w.checkReorgs(from, to, seen, reorgs)
+	} else {
+		for slotNum, sigs := range seen {
+			w.confirmedSigs[slotNum] = sigs
+		}
+	}
+
+	*last = slot
+	return nil
+}
+
+// Reobserve replays every program-relevant transaction in [fromSlot, toSlot]
+// at finalized commitment and publishes its decoded events, independent of
+// the live polling state in Run/PollOnce. This is the "observation request"
+// path: an operator forcing a re-fetch of a slot range after an outage, or a
+// backfill covering history the live watcher never saw.
+func (w *Watcher) Reobserve(ctx context.Context, fromSlot, toSlot uint64, publications chan<- MessagePublication) error {
+	if toSlot < fromSlot {
+		return fmt.Errorf("watcher: Reobserve: toSlot %d is before fromSlot %d", toSlot, fromSlot)
+	}
+
+	_, err := w.walkSlots(ctx, fromSlot, toSlot, rpc.CommitmentFinalized, publications)
+	return err
+}
+
+func (w *Watcher) lastSlotFor(commitment rpc.CommitmentType) *uint64 {
+	if commitment == rpc.CommitmentFinalized {
+		return &w.lastFinalizedSlot
+	}
+	return &w.lastConfirmedSlot
+}
+
+// walkSlots fetches every slot in [from, to] that was actually produced,
+// filters its transactions down to ones touching w.programID, decodes their
+// events, and publishes them. It returns the set of matching signatures seen
+// per slot for reorg detection.
+func (w *Watcher) walkSlots(ctx context.Context, from, to uint64, commitment rpc.CommitmentType, publications chan<- MessagePublication) (map[uint64]map[solana.Signature]bool, error) {
+	limit := to - from + 1
+	slots, err := w.client.GetBlocksWithLimit(ctx, from, limit, commitment)
+	if err != nil {
+		RPCErrorsTotal.WithLabelValues("GetBlocksWithLimit").Inc()
+		return nil, fmt.Errorf("GetBlocksWithLimit(%d, %d): %w", from, limit, err)
+	}
+
+	seen := make(map[uint64]map[solana.Signature]bool, len(*slots))
+
+	for _, slot := range *slots {
+		block, err := w.client.GetBlockWithOpts(ctx, slot, &rpc.GetBlockOpts{
+			Commitment:                     commitment,
+			TransactionDetails:             rpc.TransactionDetailsFull,
+			MaxSupportedTransactionVersion: &maxSupportedTxVersion,
+		})
+		if err != nil {
+			RPCErrorsTotal.WithLabelValues("GetBlock").Inc()
+			log.Printf("watcher: GetBlock(%d): %v", slot, err)
+			continue
+		}
+
+		slotSigs := make(map[solana.Signature]bool)
+
+		for _, txWithMeta := range block.Transactions {
+			tx, err := txWithMeta.GetTransaction()
+			if err != nil || tx == nil || len(tx.Signatures) == 0 {
+				continue
+			}
+			if !txTouchesProgram(tx, txWithMeta.Meta, w.programID) {
+				continue
+			}
+
+			sig := tx.Signatures[0]
+			slotSigs[sig] = true
+
+			decoded := w.registry.DecodeTx(&rpc.GetTransactionResult{
+				Slot: slot,
+				Meta: txWithMeta.Meta,
+			})
+
+			if commitment == rpc.CommitmentConfirmed {
+				EventsConfirmedTotal.Add(float64(len(decoded)))
+			} else {
+				EventsFinalizedTotal.Add(float64(len(decoded)))
+			}
+
+			if publications != nil {
+				publications <- MessagePublication{
+					Slot:       slot,
+					Signature:  sig,
+					Commitment: commitment,
+					Events:     decoded,
+				}
+			}
+		}
+
+		seen[slot] = slotSigs
+	}
+
+	return seen, nil
+}
+
+// checkReorgs diffs every confirmed signature recorded for [from, to]
+// against what actually finalized in that range, emitting a Reorged for any
+// that vanished.
+func (w *Watcher) checkReorgs(from, to uint64, finalized map[uint64]map[solana.Signature]bool, reorgs chan<- Reorged) {
+	for slot := from; slot <= to; slot++ {
+		confirmed, ok := w.confirmedSigs[slot]
+		if !ok {
+			continue
+		}
+
+		finalizedSigs := finalized[slot]
+		for sig := range confirmed {
+			if !finalizedSigs[sig] {
+				if reorgs != nil {
+					reorgs <- Reorged{Slot: slot, Signature: sig}
+				}
+			}
+		}
+
+		delete(w.confirmedSigs, slot)
+	}
+}
+
+// txTouchesProgram reports whether tx references programID, either in its
+// static account list or via an address-lookup-table entry resolved into
+// meta.LoadedAddresses. Version-0 transactions (maxSupportedTxVersion above)
+// can reference an account either way, and skipping the second only checks
+// silently drops transactions that reach the program purely through an ALT.
+func txTouchesProgram(tx *solana.Transaction, meta *rpc.TransactionMeta, programID solana.PublicKey) bool {
+	if accountKeysContain(tx.Message.AccountKeys, programID) {
+		return true
+	}
+	if meta == nil {
+		return false
+	}
+	return accountKeysContain(meta.LoadedAddresses.Writable, programID) ||
+		accountKeysContain(meta.LoadedAddresses.ReadOnly, programID)
+}
+
+func accountKeysContain(keys []solana.PublicKey, target solana.PublicKey) bool {
+	for _, key := range keys {
+		if key.Equals(target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package watcher
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func sig(b byte) solana.Signature {
+	var s solana.Signature
+	s[0] = b
+	return s
+}
+
+func TestCheckReorgsEmitsForSignatureMissingAtFinalization(t *testing.T) {
+	w := &Watcher{confirmedSigs: map[uint64]map[solana.Signature]bool{
+		100: {sig(1): true, sig(2): true},
+	}}
+
+	finalized := map[uint64]map[solana.Signature]bool{
+		100: {sig(1): true}, // sig(2) was confirmed but never finalized
+	}
+
+	reorgs := make(chan Reorged, 4)
+	w.checkReorgs(100, 100, finalized, reorgs)
+	close(reorgs)
+
+	var got []Reorged
+	for r := range reorgs {
+		got = append(got, r)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("expected 1 reorg, got %d: %+v", len(got), got)
+	}
+	if got[0].Slot != 100 || got[0].Signature != sig(2) {
+		t.Errorf("unexpected reorg: %+v", got[0])
+	}
+	if _, ok := w.confirmedSigs[100]; ok {
+		t.Errorf("expected confirmedSigs[100] to be cleared after checking")
+	}
+}
+
+func TestCheckReorgsSkipsSlotsNeverSeenAtConfirmed(t *testing.T) {
+	w := &Watcher{confirmedSigs: map[uint64]map[solana.Signature]bool{}}
+
+	reorgs := make(chan Reorged, 4)
+	w.checkReorgs(50, 52, nil, reorgs)
+	close(reorgs)
+
+	for r := range reorgs {
+		t.Errorf("expected no reorgs for slots never confirmed, got %+v", r)
+	}
+}
+
+func TestCheckReorgsToleratesNilReorgsChannel(t *testing.T) {
+	w := &Watcher{confirmedSigs: map[uint64]map[solana.Signature]bool{
+		10: {sig(9): true},
+	}}
+
+	w.checkReorgs(10, 10, map[uint64]map[solana.Signature]bool{}, nil)
+
+	if _, ok := w.confirmedSigs[10]; ok {
+		t.Errorf("expected confirmedSigs[10] to be cleared even with a nil reorgs channel")
+	}
+}
+
+// TestTxTouchesProgramMatchesAddressLookupTableEntry pins the ALT case: a
+// version-0 transaction that references the program only through an
+// address-lookup-table entry (resolved into meta.LoadedAddresses rather than
+// the static account list) must still be recognized, or the watcher silently
+// drops it.
+func TestTxTouchesProgramMatchesAddressLookupTableEntry(t *testing.T) {
+	programID := solana.MustPublicKeyFromBase58("9yWT9i8kJxY6JFdud9eeWkqtiMTUcDgbSCgF5RD4ihTE")
+	other := solana.MustPublicKeyFromBase58("11111111111111111111111111111111")
+
+	tx := &solana.Transaction{
+		Message: solana.Message{
+			AccountKeys: []solana.PublicKey{other},
+		},
+	}
+	meta := &rpc.TransactionMeta{
+		LoadedAddresses: rpc.LoadedAddresses{
+			Writable: solana.PublicKeySlice{programID},
+		},
+	}
+
+	if !txTouchesProgram(tx, meta, programID) {
+		t.Fatal("expected txTouchesProgram to match a program ID only present in LoadedAddresses.Writable")
+	}
+
+	meta = &rpc.TransactionMeta{
+		LoadedAddresses: rpc.LoadedAddresses{
+			ReadOnly: solana.PublicKeySlice{programID},
+		},
+	}
+	if !txTouchesProgram(tx, meta, programID) {
+		t.Fatal("expected txTouchesProgram to match a program ID only present in LoadedAddresses.ReadOnly")
+	}
+
+	unrelated := solana.MustPublicKeyFromBase58("So11111111111111111111111111111111111111112")
+	if txTouchesProgram(tx, meta, unrelated) {
+		t.Fatal("expected txTouchesProgram to report false when neither account list nor LoadedAddresses carry the target")
+	}
+}
+
+// TestResumeSetsIndependentWatermarks guards against collapsing the
+// confirmed and finalized cursors into a single value: confirmed always
+// reaches a given slot before finalized does, so restoring both to the same
+// persisted slot would fast-forward the finalized cursor past slots that
+// were confirmed but never actually processed at finalized commitment.
+func TestResumeSetsIndependentWatermarks(t *testing.T) {
+	w := &Watcher{confirmedSigs: map[uint64]map[solana.Signature]bool{}}
+	w.Resume(500, 480)
+
+	if w.lastConfirmedSlot != 500 {
+		t.Errorf("lastConfirmedSlot = %d, want 500", w.lastConfirmedSlot)
+	}
+	if w.lastFinalizedSlot != 480 {
+		t.Errorf("lastFinalizedSlot = %d, want 480", w.lastFinalizedSlot)
+	}
+}